@@ -0,0 +1,25 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOffsetOutOfRange reports that a requested offset falls outside a
+// log's currently known range: not yet written, or already truncated
+// or retained away.
+type ErrOffsetOutOfRange struct {
+	Offset uint64
+}
+
+func (e ErrOffsetOutOfRange) Error() string {
+	return fmt.Sprintf("offset out of range: %d", e.Offset)
+}
+
+// IsOffsetOutOfRange reports whether err is (or wraps) an
+// ErrOffsetOutOfRange, so callers can distinguish it from other failures
+// without matching on error text.
+func IsOffsetOutOfRange(err error) bool {
+	var target ErrOffsetOutOfRange
+	return errors.As(err, &target)
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Record is a single entry appended to and read from a commit log segment.
+type Record struct {
+	Value  []byte
+	Offset uint64
+}
+
+// Marshal encodes the record into its on-disk representation: an 8-byte
+// big-endian offset followed by the raw value bytes.
+func (r *Record) Marshal() ([]byte, error) {
+	buf := make([]byte, 8+len(r.Value))
+	binary.BigEndian.PutUint64(buf[:8], r.Offset)
+	copy(buf[8:], r.Value)
+	return buf, nil
+}
+
+// Unmarshal decodes a record previously produced by Marshal.
+func (r *Record) Unmarshal(p []byte) error {
+	if len(p) < 8 {
+		return fmt.Errorf("record too short: %d bytes", len(p))
+	}
+	r.Offset = binary.BigEndian.Uint64(p[:8])
+	r.Value = append([]byte(nil), p[8:]...)
+	return nil
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+func TestAppendConcurrentOffsetsAreUniqueAndContiguous(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-append-concurrent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 50
+	var wg sync.WaitGroup
+	offsets := make([]uint64, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			offsets[i], errs[i] = l.Append(&api.Record{Value: []byte{byte(i)}})
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[uint64]bool, n)
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Append %d: %v", i, err)
+		}
+		if seen[offsets[i]] {
+			t.Fatalf("offset %d was assigned to more than one concurrent Append", offsets[i])
+		}
+		seen[offsets[i]] = true
+	}
+	for off := uint64(0); off < n; off++ {
+		if !seen[off] {
+			t.Fatalf("assigned offsets are not contiguous: missing %d", off)
+		}
+	}
+}
+
+func TestAppendAsyncReturnsErrLogClosedAfterClose(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-append-async-closed-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	res := <-l.AppendAsync(&api.Record{Value: []byte{0}})
+	if res.Err != ErrLogClosed {
+		t.Fatalf("AppendAsync after Close: got err %v, want ErrLogClosed", res.Err)
+	}
+}
+
+// TestAppendBatchesAcrossConcurrentProducers exercises group commit under
+// MaxBatchBytes/MaxBatchDelay: many producers append concurrently with a
+// tiny batch size and a short coalescing delay, so the writer goroutine
+// is forced to batch several requests per commit. Every record must
+// still land at its assigned offset with its own content intact.
+func TestAppendBatchesAcrossConcurrentProducers(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-append-batch-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.MaxBatchBytes = 4
+	c.MaxBatchDelay = 5 * time.Millisecond
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 30
+	var (
+		mu            sync.Mutex
+		offsetToValue = make(map[uint64]byte, n)
+		wg            sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			off, err := l.Append(&api.Record{Value: []byte{byte(i)}})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			offsetToValue[off] = byte(i)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	if len(offsetToValue) != n {
+		t.Fatalf("got %d distinct offsets, want %d", len(offsetToValue), n)
+	}
+	for off, want := range offsetToValue {
+		record, err := l.Read(off)
+		if err != nil {
+			t.Fatalf("Read(%d): %v", off, err)
+		}
+		if record.Value[0] != want {
+			t.Fatalf("Read(%d): got value %v, want %v", off, record.Value, []byte{want})
+		}
+	}
+}
+
+func TestAppendSucceedsUnderEachSyncPolicy(t *testing.T) {
+	for _, policy := range []SyncPolicy{SyncNone, SyncAlways, SyncInterval} {
+		t.Run(string(policy), func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "log-sync-policy-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			var c Config
+			c.Segment.SyncPolicy = policy
+			c.Segment.SyncInterval = 5 * time.Millisecond
+			l, err := NewLog(dir, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			off, err := l.Append(&api.Record{Value: []byte("hello")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			record, err := l.Read(off)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(record.Value) != "hello" {
+				t.Fatalf("Read after Append under %s: got %q, want %q", policy, record.Value, "hello")
+			}
+		})
+	}
+}
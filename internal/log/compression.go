@@ -0,0 +1,68 @@
+package log
+
+import (
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects the codec used to compress a segment's record
+// payloads before they're written to its store.
+type Compression string
+
+const (
+	CompressionNone   Compression = "none"
+	CompressionSnappy Compression = "snappy"
+	CompressionZstd   Compression = "zstd"
+)
+
+// codec is the 1-byte tag persisted alongside every record so Read can
+// decompress it correctly regardless of the segment's or the log's
+// current Compression setting.
+type codec byte
+
+const (
+	codecNone codec = iota
+	codecSnappy
+	codecZstd
+)
+
+func codecFor(c Compression) codec {
+	switch c {
+	case CompressionSnappy:
+		return codecSnappy
+	case CompressionZstd:
+		return codecZstd
+	default:
+		return codecNone
+	}
+}
+
+// zstdEncoder and zstdDecoder are shared across segments: both are safe
+// for concurrent use, and a zstd.Encoder is too expensive to spin up per
+// record.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+func compressWith(c codec, p []byte) []byte {
+	switch c {
+	case codecSnappy:
+		return snappy.Encode(nil, p)
+	case codecZstd:
+		return zstdEncoder.EncodeAll(p, nil)
+	default:
+		return p
+	}
+}
+
+func decompressWith(c codec, p []byte) ([]byte, error) {
+	switch c {
+	case codecSnappy:
+		return snappy.Decode(nil, p)
+	case codecZstd:
+		return zstdDecoder.DecodeAll(p, nil)
+	default:
+		return p, nil
+	}
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+func TestSegmentAppendReadRoundTripsPerCodec(t *testing.T) {
+	cases := []Compression{CompressionNone, CompressionSnappy, CompressionZstd}
+
+	for _, compression := range cases {
+		t.Run(string(compression), func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "segment-compression-roundtrip-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			var c Config
+			c.Segment.MaxStoreBytes = 1 << 20
+			c.Segment.MaxIndexBytes = 1 << 16
+			c.Segment.Compression = compression
+
+			s, err := newSegment(dir, 0, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			value := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 20)
+			off, err := s.Append(&api.Record{Value: value})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			record, err := s.Read(off)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !bytes.Equal(record.Value, value) {
+				t.Fatalf("Read after Append with %s: got %q, want %q", compression, record.Value, value)
+			}
+		})
+	}
+}
+
+func TestSegmentBelowCompressionMinBytesStoredRaw(t *testing.T) {
+	dir, err := os.MkdirTemp("", "segment-compression-min-bytes-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 << 20
+	c.Segment.MaxIndexBytes = 1 << 16
+	c.Segment.Compression = CompressionSnappy
+	c.Segment.CompressionMinBytes = 1 << 10 // bigger than the record below
+
+	s, err := newSegment(dir, 0, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	value := []byte("tiny")
+	off, err := s.Append(&api.Record{Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, pos, err := s.index.Read(int64(off))
+	if err != nil {
+		t.Fatal(err)
+	}
+	framed, err := s.store.Read(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codec(framed[0]) != codecNone {
+		t.Fatalf("codec tag of a record below CompressionMinBytes: got %d, want %d (none)", framed[0], codecNone)
+	}
+
+	record, err := s.Read(off)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !bytes.Equal(record.Value, value) {
+		t.Fatalf("Read: got %q, want %q", record.Value, value)
+	}
+}
+
+func TestSegmentCodecPersistsAcrossReopenRegardlessOfConfig(t *testing.T) {
+	dir, err := os.MkdirTemp("", "segment-compression-reopen-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 << 20
+	c.Segment.MaxIndexBytes = 1 << 16
+	c.Segment.Compression = CompressionZstd
+
+	value := bytes.Repeat([]byte("reopen me "), 50)
+
+	s, err := newSegment(dir, 0, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	off, err := s.Append(&api.Record{Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen with a different (even disabled) Compression config: the
+	// segment's own header should win, and old records still decompress.
+	c.Segment.Compression = CompressionNone
+	s2, err := newSegment(dir, 0, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+
+	if s2.codec != codecZstd {
+		t.Fatalf("codec after reopen with Compression %q: got %d, want %d (zstd)", c.Segment.Compression, s2.codec, codecZstd)
+	}
+
+	record, err := s2.Read(off)
+	if err != nil {
+		t.Fatalf("Read after reopen with different Compression config: %v", err)
+	}
+	if !bytes.Equal(record.Value, value) {
+		t.Fatalf("Read after reopen: got %q, want %q", record.Value, value)
+	}
+}
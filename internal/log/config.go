@@ -0,0 +1,87 @@
+package log
+
+import "time"
+
+// Config controls how a Log stores and rolls its segments.
+type Config struct {
+	Segment struct {
+		MaxStoreBytes uint64
+		MaxIndexBytes uint64
+		InitialOffset uint64
+
+		// Compression selects the codec used for record payloads at or
+		// above CompressionMinBytes. Defaults to CompressionNone.
+		Compression Compression
+
+		// CompressionMinBytes is the smallest marshaled record size that
+		// gets compressed; smaller records are stored as-is, since the
+		// codec tag plus the compression overhead can outweigh the
+		// saving. Zero compresses every record.
+		CompressionMinBytes uint64
+
+		// MaxAge, if non-zero, forces the active segment to roll once
+		// it's been open this long, even if it hasn't filled
+		// MaxStoreBytes or MaxIndexBytes.
+		MaxAge time.Duration
+
+		// SyncPolicy controls how often Append forces the active
+		// segment's store to disk. Defaults to SyncNone.
+		SyncPolicy SyncPolicy
+
+		// SyncInterval is the period between background syncs when
+		// SyncPolicy is SyncInterval. Zero uses a sane default.
+		SyncInterval time.Duration
+	}
+
+	// MaxOpenSegments caps how many rolled (non-active) segments are kept
+	// mmap'd at once; beyond this the least-recently-used ones are
+	// unmapped and transparently reopened from disk on demand. Zero uses
+	// a sane default.
+	MaxOpenSegments uint64
+
+	// SegmentIdleTimeout, if non-zero, additionally unmaps a rolled
+	// segment once it's gone unused for this long, even if
+	// MaxOpenSegments hasn't been reached.
+	SegmentIdleTimeout time.Duration
+
+	// MaxTotalBytes, if non-zero, caps the log's cumulative on-disk size.
+	// Retain, and the background retention goroutine, delete the oldest
+	// rolled segments once it's exceeded.
+	MaxTotalBytes uint64
+
+	// MaxAge, if non-zero, caps how long a rolled segment's data is kept
+	// around. Retain deletes any rolled segment older than this,
+	// independent of MaxTotalBytes.
+	MaxAge time.Duration
+
+	// RetentionCheckInterval controls how often the background retention
+	// goroutine runs Retain and checks whether the active segment has
+	// aged past Segment.MaxAge. Zero uses a sane default. The background
+	// goroutine only runs when MaxTotalBytes, MaxAge, or Segment.MaxAge
+	// is set.
+	RetentionCheckInterval time.Duration
+
+	// OnSegmentRotated, if set, is called with a segment's base offset
+	// each time Append (or the MaxAge rotation check) rolls it out from
+	// under the active segment, so callers can archive it before Retain
+	// or Truncate might remove it. Called synchronously while Log's lock
+	// is held, so it must not call back into the Log.
+	OnSegmentRotated func(baseOffset uint64)
+
+	// OnSegmentDeleted, if set, is called with a segment's base offset
+	// immediately before Retain removes its files. Called synchronously
+	// while Log's lock is held, so it must not call back into the Log.
+	OnSegmentDeleted func(baseOffset uint64)
+
+	// MaxBatchBytes caps how many bytes of pending record values the
+	// background writer accumulates into one batch (one store write,
+	// one optional Sync) before committing it. Zero means unlimited:
+	// every batch commits everything already queued.
+	MaxBatchBytes uint64
+
+	// MaxBatchDelay, if non-zero, lets the writer linger this long
+	// after the first request in a batch arrives, so concurrent
+	// producers can coalesce into fewer, larger commits at the cost of
+	// a little latency. Zero commits as soon as the queue is drained.
+	MaxBatchDelay time.Duration
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,16 +11,59 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	api "github.com/michael-abb/commitlog/api/v1"
 )
 
+const (
+	idleEvictionInterval          = time.Second
+	defaultRetentionCheckInterval = 30 * time.Second
+	defaultSyncInterval           = time.Second
+)
+
+// ErrLogClosed is returned by AppendAsync (and, through it, Append) once
+// the log has been closed.
+var ErrLogClosed = errors.New("log: closed")
+
+// Result is the outcome of an AppendAsync request: the offset the record
+// was assigned, or the error that prevented it.
+type Result struct {
+	Offset uint64
+	Err    error
+}
+
+// writeRequest is one pending Append, queued on Log.writeReqs for the
+// background writer goroutine to batch and commit.
+type writeRequest struct {
+	record *api.Record
+	result chan Result
+}
+
 type Log struct {
 	mu            sync.RWMutex
 	Dir           string
 	c             Config
 	activeSegment *segment
-	segments      []*segment
+	activeRef     *RefCount[*segment]
+	cache         *segmentCache
+
+	// cond is broadcast whenever Append commits a record, so that tailing
+	// Readers blocked on EOF wake up and re-check for new data.
+	cond          *sync.Cond
+	closed        bool
+	stopIdle      chan struct{}
+	stopRetention chan struct{}
+	stopSyncer    chan struct{}
+
+	// writeReqs feeds the single background writer goroutine that owns
+	// all mutation of activeSegment/activeRef during normal operation,
+	// batching contiguous appends into one store write and (depending on
+	// Config.Segment.SyncPolicy) one Sync. stopWriter signals it to
+	// drain and exit; writerDone is closed once it has.
+	writeReqs  chan *writeRequest
+	stopWriter chan struct{}
+	writerDone chan struct{}
 }
 
 type originReader struct {
@@ -33,14 +77,50 @@ func (o originReader) Read(p []byte) (int, error) {
 	return n, err
 }
 
-func (l *Log) Reader() io.Reader {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// releasingReader wraps a cached segment's reader so the ref acquired to
+// build it is released as soon as that segment has been fully read,
+// rather than held for the lifetime of the whole-log Reader.
+type releasingReader struct {
+	io.Reader
+	release func()
+	done    bool
+}
 
-	readers := make([]io.Reader, len(l.segments))
-	for i, s := range l.segments {
-		readers[i] = &originReader{s.store, 0}
+func (r *releasingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && !r.done {
+		r.done = true
+		r.release()
+	}
+	return n, err
+}
+
+// Reader returns a reader over the whole log from the beginning. It
+// acquires every tracked segment in turn, releasing each one as
+// io.MultiReader finishes reading it, so a long read doesn't pin every
+// segment mapped for its whole lifetime. For large logs or for tailing
+// as the log grows, use NewReader instead.
+func (l *Log) Reader() io.Reader {
+	l.mu.RLock()
+	baseOffsets := l.cache.allBaseOffsets()
+	active := l.activeSegment
+	l.mu.RUnlock()
+
+	readers := make([]io.Reader, 0, len(baseOffsets)+1)
+	for _, baseOffset := range baseOffsets {
+		ref, err := l.cache.acquire(baseOffset)
+		if err != nil {
+			continue
+		}
+		readers = append(readers, &releasingReader{
+			Reader:  &originReader{ref.Get().store, 0},
+			release: func() { l.release(ref) },
+		})
 	}
+	if active != nil {
+		readers = append(readers, &originReader{active.store, 0})
+	}
+
 	return io.MultiReader(readers...)
 }
 
@@ -49,30 +129,63 @@ func (l *Log) Truncate(lowest uint64) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	var segs []*segment
-	for _, s := range l.segments {
-		if s.nextOffset <= lowest+1 {
-			if err := s.Remove(); err != nil {
-				return err
-			}
-			continue
-		}
-		segs = append(segs, s)
-	}
-	l.segments = segs
-	return nil
+	return l.cache.removeUpTo(lowest)
 }
 
+// newSegment hands the current active segment (if any) off to the cache
+// for on-demand reopening and opens a new active segment at off.
 func (l *Log) newSegment(off uint64) error {
+	if l.activeSegment != nil {
+		old := l.activeSegment
+		l.cache.track(old.baseOffset, off, old.store.size, old.createdAt, l.activeRef)
+		if l.c.OnSegmentRotated != nil {
+			l.c.OnSegmentRotated(old.baseOffset)
+		}
+	}
+
 	s, err := newSegment(l.Dir, off, l.c)
 	if err != nil {
 		return err
 	}
-	l.segments = append(l.segments, s)
 	l.activeSegment = s
+	l.activeRef = NewRefCount(s)
 	return nil
 }
 
+// rollIfAged forces the active segment to roll if it's outlived
+// Config.Segment.MaxAge, even though it isn't full. Append already checks
+// this on every write; this covers a log that's gone idle.
+func (l *Log) rollIfAged() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.c.Segment.MaxAge <= 0 || l.activeSegment == nil {
+		return nil
+	}
+	if l.activeSegment.nextOffset == l.activeSegment.baseOffset {
+		// Nothing's been written to it yet; rolling would just recreate
+		// an identical segment under the same base offset.
+		return nil
+	}
+	if !l.activeSegment.IsMaxed() {
+		return nil
+	}
+	return l.newSegment(l.activeSegment.nextOffset)
+}
+
+// Retain deletes rolled segments that exceed Config.MaxTotalBytes or
+// Config.MaxAge. It's a no-op when neither is configured.
+func (l *Log) Retain() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.c.MaxTotalBytes == 0 && l.c.MaxAge == 0 {
+		return nil
+	}
+
+	return l.cache.retain(l.activeSegment.store.size, l.c.MaxTotalBytes, l.c.MaxAge, l.c.OnSegmentDeleted)
+}
+
 func NewLog(dir string, c Config) (*Log, error) {
 	if c.Segment.MaxStoreBytes == 0 {
 		c.Segment.MaxStoreBytes = 1024
@@ -86,8 +199,194 @@ func NewLog(dir string, c Config) (*Log, error) {
 		Dir: dir,
 		c:   c,
 	}
+	l.cond = sync.NewCond(&l.mu)
+
+	if err := l.setup(); err != nil {
+		return nil, err
+	}
+
+	if c.SegmentIdleTimeout > 0 {
+		l.stopIdle = make(chan struct{})
+		go l.runIdleEvictor(l.stopIdle)
+	}
+
+	if c.MaxTotalBytes > 0 || c.MaxAge > 0 || c.Segment.MaxAge > 0 {
+		l.stopRetention = make(chan struct{})
+		go l.runRetentionLoop(l.stopRetention)
+	}
+
+	if c.Segment.SyncPolicy == SyncInterval {
+		l.stopSyncer = make(chan struct{})
+		go l.runSyncer(l.stopSyncer)
+	}
+
+	l.writeReqs = make(chan *writeRequest)
+	l.stopWriter = make(chan struct{})
+	l.writerDone = make(chan struct{})
+	go l.runWriter()
+
+	return l, nil
+}
+
+// runSyncer periodically syncs the active segment's store, independent of
+// Append traffic. It only runs when Config.Segment.SyncPolicy is
+// SyncInterval.
+func (l *Log) runSyncer(stop chan struct{}) {
+	interval := l.c.Segment.SyncInterval
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
 
-	return l, l.setup()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.RLock()
+			active := l.activeSegment
+			l.mu.RUnlock()
+			if active != nil {
+				_ = active.Sync()
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// runWriter is the single goroutine that owns committing Append requests.
+// Serializing every commit through one goroutine means activeSegment only
+// ever needs to be mutated from here and from the retention goroutine
+// (both under l.mu), and lets group commit batch contiguous appends into
+// one store write and one Sync.
+func (l *Log) runWriter() {
+	defer close(l.writerDone)
+
+	for {
+		select {
+		case req := <-l.writeReqs:
+			l.commitBatch(l.drainBatch(req))
+		case <-l.stopWriter:
+			return
+		}
+	}
+}
+
+// drainBatch collects every write request already queued behind first,
+// plus (if Config.MaxBatchDelay is set) any that arrive within that
+// window, up to Config.MaxBatchBytes of pending record values. This is
+// the group-commit knob: a non-zero delay trades a little latency for
+// fewer store writes and fsyncs under concurrent producers.
+func (l *Log) drainBatch(first *writeRequest) []*writeRequest {
+	batch := []*writeRequest{first}
+	size := uint64(len(first.record.Value))
+
+	var deadline <-chan time.Time
+	if l.c.MaxBatchDelay > 0 {
+		timer := time.NewTimer(l.c.MaxBatchDelay)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for l.c.MaxBatchBytes == 0 || size < l.c.MaxBatchBytes {
+		select {
+		case req := <-l.writeReqs:
+			batch = append(batch, req)
+			size += uint64(len(req.record.Value))
+			continue
+		default:
+		}
+
+		if deadline == nil {
+			return batch
+		}
+
+		select {
+		case req := <-l.writeReqs:
+			batch = append(batch, req)
+			size += uint64(len(req.record.Value))
+		case <-deadline:
+			return batch
+		}
+	}
+
+	return batch
+}
+
+// commitBatch appends every record in the batch to the active segment in
+// order, rolling it if a mid-batch append fills it, syncs according to
+// Config.Segment.SyncPolicy, then wakes tailing Readers and replies to
+// every request's result channel.
+func (l *Log) commitBatch(batch []*writeRequest) {
+	l.mu.Lock()
+
+	results := make([]Result, len(batch))
+	for i, req := range batch {
+		off, err := l.activeSegment.Append(req.record)
+		if err != nil {
+			results[i] = Result{Err: fmt.Errorf("failed to append record in log.Append, with error %w", err)}
+			continue
+		}
+
+		if l.activeSegment.IsMaxed() {
+			err = l.newSegment(off + 1)
+		}
+		results[i] = Result{Offset: off, Err: err}
+	}
+
+	if l.c.Segment.SyncPolicy == SyncAlways {
+		_ = l.activeSegment.Sync()
+	}
+
+	l.cond.Broadcast()
+	l.mu.Unlock()
+
+	for i, req := range batch {
+		req.result <- results[i]
+		close(req.result)
+	}
+}
+
+// runIdleEvictor takes stop by value rather than reading l.stopIdle on
+// every tick, since Close clears that field under l.mu without the loop
+// holding it.
+func (l *Log) runIdleEvictor(stop chan struct{}) {
+	ticker := time.NewTicker(idleEvictionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.cache.evictIdle()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// runRetentionLoop periodically forces an aged-out active segment to
+// roll and runs Retain. Both are best-effort: an error here just means
+// the next tick tries again. It takes stop by value for the same reason
+// as runIdleEvictor.
+func (l *Log) runRetentionLoop(stop chan struct{}) {
+	interval := l.c.RetentionCheckInterval
+	if interval <= 0 {
+		interval = defaultRetentionCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = l.rollIfAged()
+			_ = l.Retain()
+		case <-stop:
+			return
+		}
+	}
 }
 
 func (l *Log) setup() error {
@@ -108,65 +407,150 @@ func (l *Log) setup() error {
 	sort.Slice(offsets, func(i, j int) bool {
 		return offsets[i] < offsets[j]
 	})
-
-	for i := 0; i < len(offsets); i++ {
-		if err = l.newSegment(offsets[i]); err != nil {
-			return fmt.Errorf("failed to create new segment in log.setup with error %w", err)
-			i++
+	offsets = dedupeOffsets(offsets)
+
+	l.cache = newSegmentCache(l.Dir, l.c)
+	l.activeSegment = nil
+	l.activeRef = nil
+
+	// Every offset but the last is already rolled: its bounds can be
+	// derived from where the next segment starts, with no need to open
+	// it. Only the last (or, if none exist yet, the initial offset)
+	// becomes the active segment and is actually opened.
+	for i := 0; i < len(offsets)-1; i++ {
+		storeBytes, createdAt, err := segmentStat(l.Dir, offsets[i])
+		if err != nil {
+			return fmt.Errorf("failed to stat segment %d in log.setup with error %w", offsets[i], err)
 		}
+		l.cache.track(offsets[i], offsets[i+1], storeBytes, createdAt, nil)
 	}
 
-	if l.segments == nil {
-		if err = l.newSegment(l.c.Segment.InitialOffset); err != nil {
-			return fmt.Errorf("failed to create new segment in log.setup with error %w", err)
-		}
+	lastOffset := l.c.Segment.InitialOffset
+	if len(offsets) > 0 {
+		lastOffset = offsets[len(offsets)-1]
 	}
 
+	s, err := newSegment(l.Dir, lastOffset, l.c)
+	if err != nil {
+		return fmt.Errorf("failed to create new segment in log.setup with error %w", err)
+	}
+	l.activeSegment = s
+	l.activeRef = NewRefCount(s)
+
 	return nil
 }
 
-func (l *Log) Append(r *api.Record) (uint64, error) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// dedupeOffsets collapses a sorted slice of offsets down to its distinct
+// values. Each segment is backed by a store, an index, and a header file
+// that all share the same base offset, so the directory scan in setup
+// sees every offset three times.
+func dedupeOffsets(offsets []uint64) []uint64 {
+	out := offsets[:0]
+	for i, off := range offsets {
+		if i == 0 || off != out[len(out)-1] {
+			out = append(out, off)
+		}
+	}
+	return out
+}
 
-	off, err := l.activeSegment.Append(r)
+// AppendAsync enqueues r on the write pipeline and returns a channel that
+// receives its Result once the background writer has committed it (as
+// part of a batch with whatever else was queued at the time). Callers
+// that want to block for the result can just use Append.
+func (l *Log) AppendAsync(r *api.Record) <-chan Result {
+	result := make(chan Result, 1)
+
+	select {
+	case l.writeReqs <- &writeRequest{record: r, result: result}:
+	case <-l.stopWriter:
+		result <- Result{Err: ErrLogClosed}
+		close(result)
+	}
 
-	if err != nil {
-		return 0, fmt.Errorf("failed to append record in log.Append, with error %w", err)
+	return result
+}
+
+// Append enqueues r and blocks until the background writer has committed
+// it, returning its assigned offset.
+func (l *Log) Append(r *api.Record) (uint64, error) {
+	res := <-l.AppendAsync(r)
+	return res.Offset, res.Err
+}
+
+// acquire returns a reference-counted handle to whichever segment holds
+// off: the active segment directly, or a cached one reopened from disk on
+// demand. The caller must pass the returned ref to release exactly once.
+func (l *Log) acquire(off uint64) (*RefCount[*segment], error) {
+	l.mu.RLock()
+	active := l.activeSegment
+	activeRef := l.activeRef
+	inActive := active != nil && off >= active.baseOffset && off < active.nextOffset
+	l.mu.RUnlock()
+
+	if inActive {
+		activeRef.Acquire()
+		return activeRef, nil
 	}
 
-	if l.activeSegment.IsMaxed() {
-		err = l.newSegment(off + 1)
+	baseOffset, ok := l.cache.baseOffsetFor(off)
+	if !ok {
+		return nil, api.ErrOffsetOutOfRange{Offset: off}
 	}
-	return off, err
+	return l.cache.acquire(baseOffset)
+}
+
+func (l *Log) release(ref *RefCount[*segment]) {
+	ref.Close()
 }
 
 func (l *Log) Read(off uint64) (*api.Record, error) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	ref, err := l.acquire(off)
+	if err != nil {
+		return nil, err
+	}
+	defer l.release(ref)
 
-	var s *segment
+	return ref.Get().Read(off)
+}
 
-	for _, segment := range l.segments {
-		if s.baseOffset <= off && off < s.nextOffset {
-			s = segment
-			break
-		}
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
 	}
+	l.closed = true
 
-	if s == nil || s.nextOffset <= off {
-		return nil, fmt.Errorf("offset out of range: %d", off)
+	if l.stopIdle != nil {
+		close(l.stopIdle)
+		l.stopIdle = nil
+	}
+	if l.stopRetention != nil {
+		close(l.stopRetention)
+		l.stopRetention = nil
+	}
+	if l.stopSyncer != nil {
+		close(l.stopSyncer)
+		l.stopSyncer = nil
 	}
+	close(l.stopWriter)
+	l.cond.Broadcast()
+	l.mu.Unlock()
 
-	return s.Read(off)
-}
+	// Wait for the writer to finish committing whatever batch was
+	// already in flight before touching the segments it owns.
+	<-l.writerDone
 
-func (l *Log) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	for _, s := range l.segments {
-		if err := s.Close(); err != nil {
+	if err := l.cache.closeAll(); err != nil {
+		return err
+	}
+
+	if l.activeSegment != nil {
+		if err := l.activeSegment.Close(); err != nil {
 			return err
 		}
 	}
@@ -191,19 +575,23 @@ func (l *Log) Reset() error {
 }
 
 func (l *Log) LowestOffset() (uint64, error) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	return l.segments[0].baseOffset, nil
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if lowest, ok := l.cache.lowest(); ok {
+		return lowest, nil
+	}
+	return l.activeSegment.baseOffset, nil
 }
 
 func (l *Log) HighestOffset() (uint64, error) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 
-	off := l.segments[len(l.segments)-1].nextOffset
+	off := l.activeSegment.nextOffset
 	if off == 0 {
 		return 0, nil
 	}
 
 	return off - 1, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,43 @@
+package log
+
+import (
+	"os"
+	"testing"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+func benchmarkLogAppendConcurrency(b *testing.B, parallelism int) {
+	dir, err := os.MkdirTemp("", "log-append-concurrency-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 << 30
+	c.Segment.MaxIndexBytes = 1 << 26
+
+	l, err := NewLog(dir, c)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer l.Close()
+
+	value := []byte(`{"level":"info","msg":"request handled","status":200}`)
+
+	b.SetParallelism(parallelism)
+	b.ResetTimer()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := l.Append(&api.Record{Value: value}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkLogAppendConcurrency1(b *testing.B)  { benchmarkLogAppendConcurrency(b, 1) }
+func BenchmarkLogAppendConcurrency8(b *testing.B)  { benchmarkLogAppendConcurrency(b, 8) }
+func BenchmarkLogAppendConcurrency64(b *testing.B) { benchmarkLogAppendConcurrency(b, 64) }
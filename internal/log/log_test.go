@@ -0,0 +1,125 @@
+package log
+
+import (
+	"os"
+	"testing"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+func TestLogReadEmptyLog(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-read-empty-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	_, err = l.Read(0)
+	if !api.IsOffsetOutOfRange(err) {
+		t.Fatalf("Read on an empty log: got %v, want an ErrOffsetOutOfRange", err)
+	}
+}
+
+func TestLogReadAcrossSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-read-multi-segment-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 // force a roll after every record
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 5
+	for i := 0; i < n; i++ {
+		off, err := l.Append(&api.Record{Value: []byte{byte(i)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if off != uint64(i) {
+			t.Fatalf("Append %d: got offset %d, want %d", i, off, i)
+		}
+	}
+
+	cases := []struct {
+		name string
+		off  uint64
+	}{
+		{"first segment", 0},
+		{"middle segment", 2},
+		{"last rolled segment", n - 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			record, err := l.Read(tc.off)
+			if err != nil {
+				t.Fatalf("Read(%d): %v", tc.off, err)
+			}
+			if record.Value[0] != byte(tc.off) {
+				t.Fatalf("Read(%d): got value %v, want %v", tc.off, record.Value, []byte{byte(tc.off)})
+			}
+		})
+	}
+
+	t.Run("out of range", func(t *testing.T) {
+		_, err := l.Read(n)
+		if !api.IsOffsetOutOfRange(err) {
+			t.Fatalf("Read(%d): got %v, want an ErrOffsetOutOfRange", n, err)
+		}
+	})
+}
+
+func TestLogReadTruncatedRange(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-read-truncated-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 // force a roll after every record
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 5
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.Truncate(2); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, off := range []uint64{0, 1, 2} {
+		t.Run("truncated away", func(t *testing.T) {
+			_, err := l.Read(off)
+			if !api.IsOffsetOutOfRange(err) {
+				t.Fatalf("Read(%d) after Truncate(2): got %v, want an ErrOffsetOutOfRange", off, err)
+			}
+		})
+	}
+
+	record, err := l.Read(3)
+	if err != nil {
+		t.Fatalf("Read(3) after Truncate(2): %v", err)
+	}
+	if record.Value[0] != 3 {
+		t.Fatalf("Read(3) after Truncate(2): got value %v, want [3]", record.Value)
+	}
+}
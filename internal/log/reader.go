@@ -0,0 +1,172 @@
+package log
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+// Reader streams a Log's records, each as an 8-byte big-endian length
+// prefix followed by the record's uncompressed marshaled bytes (the
+// store's per-record codec tag is not included), starting at an arbitrary logical record
+// offset and continuing transparently across segment boundaries. It
+// implements io.Reader, io.ReaderAt, and io.Seeker, all addressed in
+// record offsets rather than raw bytes, so a consumer can seek to a
+// specific offset or follow the log the way a Kafka consumer would.
+type Reader struct {
+	l      *Log
+	off    uint64
+	buf    []byte
+	ctx    context.Context
+	follow bool
+}
+
+// NewReader returns a Reader positioned at the given logical record offset.
+func NewReader(l *Log, offset uint64) (*Reader, error) {
+	return &Reader{l: l, off: offset}, nil
+}
+
+// Follow puts the Reader into tailing mode: once it catches up to the end
+// of the log, Read blocks until Append produces a new record or ctx is
+// cancelled, instead of returning io.EOF.
+func (r *Reader) Follow(ctx context.Context) *Reader {
+	r.ctx = ctx
+	r.follow = true
+	return r
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// fill loads the record at the reader's current offset into buf, as an
+// 8-byte length prefix plus its uncompressed marshaled bytes (no codec
+// tag), and advances the offset.
+// If the offset is merely unwritten so far, fill either blocks (Follow) or
+// reports io.EOF, same as any other io.Reader catching up to the end of
+// its source. If it's already been truncated or retained away, it
+// reports the underlying api.ErrOffsetOutOfRange instead, so a caller
+// checking api.IsOffsetOutOfRange can tell the two apart.
+func (r *Reader) fill() error {
+	for {
+		ref, err := r.l.acquire(r.off)
+		if err != nil {
+			if api.IsOffsetOutOfRange(err) {
+				if lowest, lerr := r.l.LowestOffset(); lerr == nil && r.off < lowest {
+					return err
+				}
+			}
+			if !r.follow {
+				return io.EOF
+			}
+			if err := r.l.waitForOffset(r.ctx, r.off); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rec, err := ref.Get().Read(r.off)
+		r.l.release(ref)
+		if err != nil {
+			return err
+		}
+
+		raw, err := rec.Marshal()
+		if err != nil {
+			return err
+		}
+
+		framed := make([]byte, lenWidth+len(raw))
+		binary.BigEndian.PutUint64(framed[:lenWidth], uint64(len(raw)))
+		copy(framed[lenWidth:], raw)
+
+		r.buf = framed
+		r.off++
+		return nil
+	}
+}
+
+// ReadAt reads, starting at logical record offset off, as many consecutive
+// records as needed to fill p.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	sub := &Reader{l: r.l, off: uint64(off)}
+
+	total := 0
+	for total < len(p) {
+		n, err := sub.Read(p[total:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Seek repositions the reader by logical record offset, not byte offset.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	var newOff int64
+	switch whence {
+	case io.SeekStart:
+		newOff = offset
+	case io.SeekCurrent:
+		newOff = int64(r.off) + offset
+	case io.SeekEnd:
+		highest, err := r.l.HighestOffset()
+		if err != nil {
+			return 0, err
+		}
+		newOff = int64(highest) + 1 + offset
+	default:
+		return 0, fmt.Errorf("log: invalid whence %d", whence)
+	}
+
+	if newOff < 0 {
+		return 0, fmt.Errorf("log: negative offset %d", newOff)
+	}
+
+	r.off = uint64(newOff)
+	r.buf = nil
+	return newOff, nil
+}
+
+// waitForOffset blocks until off has been written, the log is closed, or
+// ctx is cancelled.
+func (l *Log) waitForOffset(ctx context.Context, off uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ctx != nil && ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, func() {
+			l.mu.Lock()
+			l.cond.Broadcast()
+			l.mu.Unlock()
+		})
+		defer stop()
+	}
+
+	for {
+		if l.closed {
+			return io.EOF
+		}
+		if l.activeSegment != nil && off < l.activeSegment.nextOffset {
+			return nil
+		}
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		l.cond.Wait()
+	}
+}
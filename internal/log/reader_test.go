@@ -0,0 +1,189 @@
+package log
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+func TestReaderSeeksAcrossSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "reader-seek-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 // force a roll after every record
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 5
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := NewReader(l, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []byte{2, 3, 4} {
+		var buf [lenWidth + 8 + 1]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			t.Fatalf("read at starting offset 2: %v", err)
+		}
+		if got := buf[len(buf)-1]; got != want {
+			t.Fatalf("read record value: got %d, want %d", got, want)
+		}
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var buf [lenWidth + 8 + 1]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		t.Fatalf("read after Seek(0, SeekStart): %v", err)
+	}
+	if got := buf[len(buf)-1]; got != 0 {
+		t.Fatalf("read after Seek(0, SeekStart): got value %d, want 0", got)
+	}
+}
+
+func TestReaderFollowBlocksThenWakesOnAppend(t *testing.T) {
+	dir, err := os.MkdirTemp("", "reader-follow-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if _, err := l.Append(&api.Record{Value: []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(l, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Follow(ctx)
+
+	done := make(chan error, 1)
+	go func() {
+		var buf [lenWidth + 8 + 1]byte
+		_, err := io.ReadFull(r, buf[:])
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Follow read returned before any record was appended at offset 1: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := l.Append(&api.Record{Value: []byte{1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Follow read after Append: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Follow read never woke up after Append")
+	}
+}
+
+func TestReaderAtTruncatedOffsetReturnsOffsetOutOfRange(t *testing.T) {
+	dir, err := os.MkdirTemp("", "reader-truncated-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 // force a roll after every record
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 5
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.Truncate(2); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(l, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf [1]byte
+	_, err = r.Read(buf[:])
+	if !api.IsOffsetOutOfRange(err) {
+		t.Fatalf("Read at an offset truncated away: got %v, want an ErrOffsetOutOfRange", err)
+	}
+}
+
+func TestReaderFollowUnblocksOnContextCancel(t *testing.T) {
+	dir, err := os.MkdirTemp("", "reader-follow-cancel-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewLog(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	r, err := NewReader(l, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Follow(ctx)
+
+	done := make(chan error, 1)
+	go func() {
+		var buf [1]byte
+		_, err := r.Read(buf[:])
+		done <- err
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Follow read after context cancel: got nil error, want context.Canceled")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Follow read never woke up after context cancel")
+	}
+}
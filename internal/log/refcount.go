@@ -0,0 +1,42 @@
+package log
+
+import "sync/atomic"
+
+// RefCount wraps a value behind an atomic reference count so a shared,
+// expensive-to-open resource (a cached segment's mmap, for example) can be
+// evicted only once nobody still holds it. It starts at a count of one,
+// representing the caller of NewRefCount's own hold.
+type RefCount[T any] struct {
+	val T
+	n   atomic.Int32
+}
+
+// NewRefCount wraps val with an initial reference count of one.
+func NewRefCount[T any](val T) *RefCount[T] {
+	rc := &RefCount[T]{val: val}
+	rc.n.Store(1)
+	return rc
+}
+
+// Acquire adds a reference and returns the wrapped value.
+func (r *RefCount[T]) Acquire() T {
+	r.n.Add(1)
+	return r.val
+}
+
+// Get returns the wrapped value without affecting the reference count.
+func (r *RefCount[T]) Get() T {
+	return r.val
+}
+
+// Close releases one reference and returns the resulting count. A caller
+// that drives the count to zero is responsible for actually releasing the
+// underlying resource.
+func (r *RefCount[T]) Close() int32 {
+	return r.n.Add(-1)
+}
+
+// count reports the current reference count.
+func (r *RefCount[T]) count() int32 {
+	return r.n.Load()
+}
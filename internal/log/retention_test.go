@@ -0,0 +1,265 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+func TestRollIfAgedRotatesAgedActiveSegmentAndFiresHook(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-roll-if-aged-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxAge = 5 * time.Millisecond
+	var rotated []uint64
+	c.OnSegmentRotated = func(baseOffset uint64) { rotated = append(rotated, baseOffset) }
+
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if _, err := l.Append(&api.Record{Value: []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := l.cache.allBaseOffsets(); len(got) != 0 {
+		t.Fatalf("tracked segments before the active one ages out: got %v, want none", got)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := l.rollIfAged(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := l.cache.allBaseOffsets(); len(got) != 1 || got[0] != 0 {
+		t.Fatalf("tracked segments after rollIfAged: got %v, want [0]", got)
+	}
+	if len(rotated) != 1 || rotated[0] != 0 {
+		t.Fatalf("OnSegmentRotated calls: got %v, want [0]", rotated)
+	}
+
+	record, err := l.Read(0)
+	if err != nil {
+		t.Fatalf("Read(0) after the rotation: %v", err)
+	}
+	if record.Value[0] != 0 {
+		t.Fatalf("Read(0) after the rotation: got %v, want [0]", record.Value)
+	}
+}
+
+func TestRollIfAgedIsNoOpForAnEmptyActiveSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-roll-if-aged-empty-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxAge = time.Millisecond
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	time.Sleep(10 * time.Millisecond)
+
+	if err := l.rollIfAged(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := l.cache.allBaseOffsets(); len(got) != 0 {
+		t.Fatalf("rollIfAged rolled an active segment nothing was ever appended to: got tracked %v", got)
+	}
+}
+
+func TestRetainDeletesOldestSegmentsOverMaxTotalBytes(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-retain-size-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 // force a roll after every record
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 5
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := l.cache.allBaseOffsets()
+	if len(before) == 0 {
+		t.Fatal("expected at least one rolled segment before Retain")
+	}
+
+	var deleted []uint64
+	l.c.OnSegmentDeleted = func(baseOffset uint64) { deleted = append(deleted, baseOffset) }
+	l.c.MaxTotalBytes = 1 // budget smaller than a single rolled segment
+
+	if err := l.Retain(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deleted) == 0 {
+		t.Fatal("Retain with a tight MaxTotalBytes deleted nothing")
+	}
+
+	after := l.cache.allBaseOffsets()
+	if len(after) >= len(before) {
+		t.Fatalf("tracked segments after Retain: got %d, want fewer than %d", len(after), len(before))
+	}
+
+	for _, baseOffset := range deleted {
+		storePath := path.Join(dir, fmt.Sprintf("%d.store", baseOffset))
+		if _, err := os.Stat(storePath); !os.IsNotExist(err) {
+			t.Fatalf("segment %d's store file still exists after Retain deleted it", baseOffset)
+		}
+	}
+}
+
+func TestRetainDeletesSegmentsOlderThanMaxAge(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-retain-age-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if _, err := l.Append(&api.Record{Value: []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append(&api.Record{Value: []byte{1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	l.c.MaxAge = time.Millisecond
+
+	if err := l.Retain(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l.cache.allBaseOffsets()) != 0 {
+		t.Fatalf("tracked segments after Retain with MaxAge: got %v, want none", l.cache.allBaseOffsets())
+	}
+}
+
+// TestRetainDefersDeletionWhileSegmentIsReferenced is a regression test: a
+// segment still held by an in-flight Read/Reader must not have its mmap
+// and files removed out from under that caller, even though it's over
+// MaxTotalBytes.
+func TestRetainDefersDeletionWhileSegmentIsReferenced(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-retain-refcount-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if _, err := l.Append(&api.Record{Value: []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append(&api.Record{Value: []byte{1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	held, err := l.acquire(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.c.MaxTotalBytes = 1
+	if err := l.Retain(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := held.Get().Read(0); err != nil {
+		t.Fatalf("Read through a ref held across Retain: %v (files removed while still referenced)", err)
+	}
+
+	l.release(held)
+
+	// Once released, a later Retain should be free to finish the job.
+	if err := l.Retain(); err != nil {
+		t.Fatal(err)
+	}
+	for _, baseOffset := range l.cache.allBaseOffsets() {
+		if baseOffset == 0 {
+			t.Fatal("segment 0 still tracked after the holder released it and Retain ran again")
+		}
+	}
+}
+
+// TestTruncateDefersDeletionWhileSegmentIsReferenced mirrors the Retain
+// regression above for Truncate, which goes through the same
+// removeUpTo code path.
+func TestTruncateDefersDeletionWhileSegmentIsReferenced(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-truncate-refcount-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	held, err := l.acquire(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Truncate(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := held.Get().Read(0); err != nil {
+		t.Fatalf("Read through a ref held across Truncate: %v (files removed while still referenced)", err)
+	}
+
+	l.release(held)
+}
+
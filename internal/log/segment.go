@@ -0,0 +1,221 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+// segment pairs a store and its index and owns one (store, index, header)
+// file triple on disk, named after the logical offset of its first record.
+type segment struct {
+	dir                    string
+	store                  *store
+	index                  *index
+	baseOffset, nextOffset uint64
+	config                 Config
+
+	// codec is the compression codec this segment was created with. It's
+	// pinned at creation via the segment's header file, so a segment
+	// keeps compressing new appends consistently even if
+	// Config.Segment.Compression changes before the log is reopened.
+	codec codec
+
+	// createdAt is also pinned via the header file, so Config.Segment.MaxAge
+	// rotation survives a process restart.
+	createdAt time.Time
+}
+
+func newSegment(dir string, baseOffset uint64, c Config) (*segment, error) {
+	s := &segment{
+		dir:        dir,
+		baseOffset: baseOffset,
+		config:     c,
+	}
+
+	storeFile, err := os.OpenFile(
+		path.Join(dir, fmt.Sprintf("%d%s", baseOffset, ".store")),
+		os.O_RDWR|os.O_CREATE|os.O_APPEND,
+		0644,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if s.store, err = newStore(storeFile); err != nil {
+		return nil, err
+	}
+
+	indexFile, err := os.OpenFile(
+		path.Join(dir, fmt.Sprintf("%d%s", baseOffset, ".index")),
+		os.O_RDWR|os.O_CREATE,
+		0644,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if s.index, err = newIndex(indexFile, c); err != nil {
+		return nil, err
+	}
+
+	if s.codec, s.createdAt, err = loadSegmentHeader(path.Join(dir, s.headerName()), codecFor(c.Segment.Compression)); err != nil {
+		return nil, err
+	}
+
+	if off, _, err := s.index.Read(-1); err != nil {
+		s.nextOffset = baseOffset
+	} else {
+		s.nextOffset = baseOffset + uint64(off) + 1
+	}
+
+	return s, nil
+}
+
+// loadSegmentHeader returns the codec and creation time recorded in a
+// segment's header file, creating the header with want and the current
+// time if this is a brand new segment. The header is a fixed 9 bytes: a
+// 1-byte codec tag followed by an 8-byte big-endian creation time
+// (UnixNano).
+func loadSegmentHeader(headerPath string, want codec) (codec, time.Time, error) {
+	b, err := os.ReadFile(headerPath)
+	if err == nil && len(b) >= 9 {
+		return codec(b[0]), time.Unix(0, int64(enc.Uint64(b[1:9]))), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return 0, time.Time{}, err
+	}
+
+	now := time.Now()
+	buf := make([]byte, 9)
+	buf[0] = byte(want)
+	enc.PutUint64(buf[1:9], uint64(now.UnixNano()))
+	if err := os.WriteFile(headerPath, buf, 0644); err != nil {
+		return 0, time.Time{}, err
+	}
+	return want, now, nil
+}
+
+// segmentStat reads a segment's on-disk size and creation time without
+// mapping its store or index, so the cache can catalog a rolled segment
+// it hasn't had to reopen yet.
+func segmentStat(dir string, baseOffset uint64) (storeBytes uint64, createdAt time.Time, err error) {
+	fi, err := os.Stat(path.Join(dir, fmt.Sprintf("%d.store", baseOffset)))
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	_, createdAt, err = loadSegmentHeader(path.Join(dir, fmt.Sprintf("%d.header", baseOffset)), codecNone)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return uint64(fi.Size()), createdAt, nil
+}
+
+// Append writes a record to the segment and returns its assigned offset.
+// Records at or above Config.Segment.CompressionMinBytes are compressed
+// with the segment's codec; every record carries a 1-byte tag recording
+// which codec (if any) was actually used, so Read never has to guess.
+func (s *segment) Append(record *api.Record) (offset uint64, err error) {
+	cur := s.nextOffset
+	record.Offset = cur
+
+	raw, err := record.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	tag := codecNone
+	payload := raw
+	if s.codec != codecNone && uint64(len(raw)) >= s.config.Segment.CompressionMinBytes {
+		tag = s.codec
+		payload = compressWith(s.codec, raw)
+	}
+
+	framed := make([]byte, 1+len(payload))
+	framed[0] = byte(tag)
+	copy(framed[1:], payload)
+
+	_, pos, err := s.store.Append(framed)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = s.index.Write(uint32(s.nextOffset-s.baseOffset), pos); err != nil {
+		return 0, err
+	}
+	s.nextOffset++
+
+	return cur, nil
+}
+
+// Read returns the record at the given absolute offset, transparently
+// decompressing it according to its framing's codec tag.
+func (s *segment) Read(off uint64) (*api.Record, error) {
+	_, pos, err := s.index.Read(int64(off - s.baseOffset))
+	if err != nil {
+		return nil, err
+	}
+
+	framed, err := s.store.Read(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := decompressWith(codec(framed[0]), framed[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	record := &api.Record{}
+	if err = record.Unmarshal(raw); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// IsMaxed reports whether the segment has grown past its configured store
+// or index size, or outlived Config.Segment.MaxAge, and should be rolled.
+func (s *segment) IsMaxed() bool {
+	return s.store.size >= s.config.Segment.MaxStoreBytes ||
+		s.index.size >= s.config.Segment.MaxIndexBytes ||
+		(s.config.Segment.MaxAge > 0 && time.Since(s.createdAt) >= s.config.Segment.MaxAge)
+}
+
+// Sync flushes and fsyncs the segment's store.
+func (s *segment) Sync() error {
+	return s.store.Sync()
+}
+
+// Remove closes and deletes the segment's store, index, and header files.
+func (s *segment) Remove() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.store.Name()); err != nil {
+		return err
+	}
+	if err := os.Remove(s.index.Name()); err != nil {
+		return err
+	}
+	if err := os.Remove(path.Join(s.dir, s.headerName())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *segment) headerName() string {
+	return fmt.Sprintf("%d.header", s.baseOffset)
+}
+
+func (s *segment) Close() error {
+	if err := s.index.Close(); err != nil {
+		return err
+	}
+	if err := s.store.Close(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+func benchmarkSegmentAppend(b *testing.B, compression Compression) {
+	dir, err := os.MkdirTemp("", "segment-compression-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 << 30
+	c.Segment.MaxIndexBytes = 1 << 26
+	c.Segment.Compression = compression
+
+	s, err := newSegment(dir, 0, c)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+
+	// A realistic, moderately compressible payload: a single JSON log
+	// line, appended unchanged as every record.
+	value := []byte(fmt.Sprintf(`{"level":"info","msg":"request handled","path":"/v1/widgets/%d","status":200}`, 0))
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Append(&api.Record{Value: value}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	fi, err := os.Stat(s.store.Name())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(float64(fi.Size())/float64(b.N), "bytes/record")
+}
+
+func BenchmarkSegmentAppendNone(b *testing.B)   { benchmarkSegmentAppend(b, CompressionNone) }
+func BenchmarkSegmentAppendSnappy(b *testing.B) { benchmarkSegmentAppend(b, CompressionSnappy) }
+func BenchmarkSegmentAppendZstd(b *testing.B)   { benchmarkSegmentAppend(b, CompressionZstd) }
@@ -0,0 +1,345 @@
+package log
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+const defaultMaxOpenSegments = 16
+
+// cachedSegment is a rolled, read-only segment the cache may have mapped
+// in memory. ref is nil whenever the segment's store and index aren't
+// currently mapped; nextOffset is always known, since it's fixed the
+// moment a segment stops being active.
+type cachedSegment struct {
+	baseOffset uint64
+	nextOffset uint64
+	storeBytes uint64
+	createdAt  time.Time
+
+	ref      *RefCount[*segment]
+	lastUsed time.Time
+	elem     *list.Element // this entry's node in lru, nil while unmapped
+}
+
+// segmentCache keeps only the MaxOpenSegments most-recently-used rolled
+// segments mapped in memory, reopening older ones from disk on demand. The
+// active segment is never tracked here: Log owns it directly for as long
+// as it's being appended to. Eviction only unmaps a segment once its
+// RefCount has dropped back to the cache's own baseline hold, so an
+// in-flight Read or Reader can't have its mmap pulled out from under it.
+type segmentCache struct {
+	mu      sync.Mutex
+	dir     string
+	config  Config
+	maxOpen int
+	idleTTL time.Duration
+
+	byOffset map[uint64]*cachedSegment
+	order    []uint64 // baseOffsets, ascending
+	lru      *list.List
+}
+
+func newSegmentCache(dir string, c Config) *segmentCache {
+	maxOpen := int(c.MaxOpenSegments)
+	if maxOpen <= 0 {
+		maxOpen = defaultMaxOpenSegments
+	}
+
+	return &segmentCache{
+		dir:      dir,
+		config:   c,
+		maxOpen:  maxOpen,
+		idleTTL:  c.SegmentIdleTimeout,
+		byOffset: make(map[uint64]*cachedSegment),
+		lru:      list.New(),
+	}
+}
+
+// track registers a rolled segment's offset range, on-disk size, and
+// creation time in the catalog. ref, if non-nil, is the already-open
+// handle Log hands off when a segment stops being active, so it doesn't
+// need to be reopened on first use.
+func (c *segmentCache) track(baseOffset, nextOffset, storeBytes uint64, createdAt time.Time, ref *RefCount[*segment]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cs := &cachedSegment{
+		baseOffset: baseOffset,
+		nextOffset: nextOffset,
+		storeBytes: storeBytes,
+		createdAt:  createdAt,
+		ref:        ref,
+	}
+	if _, exists := c.byOffset[baseOffset]; !exists {
+		c.order = append(c.order, baseOffset)
+	}
+	c.byOffset[baseOffset] = cs
+
+	if ref != nil {
+		cs.lastUsed = time.Now()
+		cs.elem = c.lru.PushFront(cs)
+		c.evictLocked()
+	}
+}
+
+// baseOffsetFor returns, via binary search over the catalog, the base
+// offset of the tracked segment containing off.
+func (c *segmentCache) baseOffsetFor(off uint64) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := sort.Search(len(c.order), func(i int) bool {
+		return c.byOffset[c.order[i]].nextOffset > off
+	})
+	if i == len(c.order) || off < c.order[i] {
+		return 0, false
+	}
+	return c.order[i], true
+}
+
+// allBaseOffsets returns a snapshot of the tracked base offsets, ascending.
+func (c *segmentCache) allBaseOffsets() []uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]uint64, len(c.order))
+	copy(out, c.order)
+	return out
+}
+
+// lowest returns the smallest tracked base offset, if any.
+func (c *segmentCache) lowest() (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.order) == 0 {
+		return 0, false
+	}
+	return c.order[0], true
+}
+
+// acquire returns a reference-counted handle to the segment with the given
+// base offset, reopening it from disk if it isn't currently mapped. The
+// caller must release the returned ref exactly once (see Log.release).
+func (c *segmentCache) acquire(baseOffset uint64) (*RefCount[*segment], error) {
+	c.mu.Lock()
+	cs, ok := c.byOffset[baseOffset]
+	if !ok {
+		c.mu.Unlock()
+		return nil, api.ErrOffsetOutOfRange{Offset: baseOffset}
+	}
+
+	if cs.ref != nil {
+		cs.ref.Acquire()
+		cs.lastUsed = time.Now()
+		c.lru.MoveToFront(cs.elem)
+		c.mu.Unlock()
+		return cs.ref, nil
+	}
+	c.mu.Unlock()
+
+	seg, err := newSegment(c.dir, baseOffset, c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Truncate/Retain may have removed this baseOffset entirely while we
+	// had the lock released to reopen it: its refcount gate only protects
+	// already-mapped segments, and this one had cs.ref == nil. Don't
+	// resurrect it by wiring our freshly (re)created files back into a
+	// catalog entry nobody can reach any more; undo the reopen instead.
+	cs, ok = c.byOffset[baseOffset]
+	if !ok {
+		_ = seg.Close()
+		_ = removeSegmentFiles(c.dir, baseOffset)
+		return nil, api.ErrOffsetOutOfRange{Offset: baseOffset}
+	}
+
+	// Someone else may have reopened this segment while we didn't hold
+	// the lock; keep whichever handle won and close the loser.
+	if cs.ref != nil {
+		cs.ref.Acquire()
+		cs.lastUsed = time.Now()
+		c.lru.MoveToFront(cs.elem)
+		_ = seg.Close()
+		return cs.ref, nil
+	}
+
+	cs.ref = NewRefCount(seg)
+	cs.ref.Acquire()
+	cs.lastUsed = time.Now()
+	cs.elem = c.lru.PushFront(cs)
+	c.evictLocked()
+
+	return cs.ref, nil
+}
+
+// evictIdle unmaps any tracked segment that's unused (refcount down to the
+// cache's own hold) and has sat idle past idleTTL. It's a no-op when
+// idleTTL is zero.
+func (c *segmentCache) evictIdle() {
+	if c.idleTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for _, baseOffset := range c.order {
+		cs := c.byOffset[baseOffset]
+		if cs.ref != nil && cs.ref.count() == 1 && now.Sub(cs.lastUsed) > c.idleTTL {
+			c.unmapLocked(cs)
+		}
+	}
+}
+
+// evictLocked unmaps least-recently-used, unreferenced segments until the
+// cache is back within maxOpen. Callers must hold c.mu.
+func (c *segmentCache) evictLocked() {
+	for e := c.lru.Back(); e != nil && c.lru.Len() > c.maxOpen; {
+		cs := e.Value.(*cachedSegment)
+		prev := e.Prev()
+		if cs.ref.count() == 1 {
+			c.unmapLocked(cs)
+		}
+		e = prev
+	}
+}
+
+// unmapLocked closes a cached segment's store and index and drops it from
+// the lru, leaving its offset range tracked so it can be reopened later.
+// Callers must hold c.mu.
+func (c *segmentCache) unmapLocked(cs *cachedSegment) {
+	_ = cs.ref.Get().Close()
+	c.lru.Remove(cs.elem)
+	cs.elem = nil
+	cs.ref = nil
+}
+
+// removeUpTo deletes every tracked segment whose highest offset is at or
+// below lowest (nextOffset <= lowest+1), as Log.Truncate always has. A
+// segment still held by an
+// in-flight Read or Reader (cs.ref.count() > 1) is left in place, exactly
+// like evictLocked: it's retried on the next Truncate or Retain call once
+// the holder releases it, rather than having its mmap and files ripped
+// out from under that caller.
+func (c *segmentCache) removeUpTo(lowest uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var kept []uint64
+	for _, baseOffset := range c.order {
+		cs := c.byOffset[baseOffset]
+		if cs.nextOffset > lowest+1 || (cs.ref != nil && cs.ref.count() > 1) {
+			kept = append(kept, baseOffset)
+			continue
+		}
+
+		if cs.ref != nil {
+			if err := cs.ref.Get().Remove(); err != nil {
+				return err
+			}
+			c.lru.Remove(cs.elem)
+		} else if err := removeSegmentFiles(c.dir, baseOffset); err != nil {
+			return err
+		}
+		delete(c.byOffset, baseOffset)
+	}
+	c.order = kept
+
+	return nil
+}
+
+// retain deletes the oldest tracked segments that are no longer needed:
+// those pushing cumulative bytes (activeBytes, the live size of the
+// segment Log is still appending to, plus every tracked segment's size)
+// over maxTotalBytes, and those whose creation time is older than maxAge.
+// Either limit may be zero to disable it. onDeleted, if non-nil, is
+// called with a segment's base offset right before its files are removed.
+//
+// A segment still held by an in-flight Read or Reader (cs.ref.count() > 1)
+// is kept regardless of age or budget, exactly like evictLocked: its bytes
+// stay counted against the budget, and the next Retain call retries it
+// once the holder releases it, rather than pulling its mmap and files out
+// from under that caller.
+func (c *segmentCache) retain(activeBytes, maxTotalBytes uint64, maxAge time.Duration, onDeleted func(uint64)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := activeBytes
+	for _, baseOffset := range c.order {
+		total += c.byOffset[baseOffset].storeBytes
+	}
+
+	now := time.Now()
+	var kept []uint64
+	for _, baseOffset := range c.order {
+		cs := c.byOffset[baseOffset]
+
+		tooOld := maxAge > 0 && now.Sub(cs.createdAt) > maxAge
+		overBudget := maxTotalBytes > 0 && total > maxTotalBytes
+		if (!tooOld && !overBudget) || (cs.ref != nil && cs.ref.count() > 1) {
+			kept = append(kept, baseOffset)
+			continue
+		}
+
+		if onDeleted != nil {
+			onDeleted(baseOffset)
+		}
+
+		if cs.ref != nil {
+			if err := cs.ref.Get().Remove(); err != nil {
+				return err
+			}
+			c.lru.Remove(cs.elem)
+		} else if err := removeSegmentFiles(c.dir, baseOffset); err != nil {
+			return err
+		}
+		delete(c.byOffset, baseOffset)
+		total -= cs.storeBytes
+	}
+	c.order = kept
+
+	return nil
+}
+
+// closeAll unmaps every currently-mapped tracked segment without removing
+// it from the catalog, for use during Log.Close.
+func (c *segmentCache) closeAll() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, baseOffset := range c.order {
+		cs := c.byOffset[baseOffset]
+		if cs.ref != nil {
+			if err := cs.ref.Get().Close(); err != nil {
+				return err
+			}
+			cs.elem = nil
+			cs.ref = nil
+		}
+	}
+	return nil
+}
+
+func removeSegmentFiles(dir string, baseOffset uint64) error {
+	for _, ext := range []string{"store", "index", "header"} {
+		name := path.Join(dir, fmt.Sprintf("%d.%s", baseOffset, ext))
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	api "github.com/michael-abb/commitlog/api/v1"
+)
+
+func TestSegmentCacheEvictionRespectsRefCount(t *testing.T) {
+	dir, err := os.MkdirTemp("", "segment-cache-evict-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 // force a roll after every record
+	c.MaxOpenSegments = 1
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 3
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Hold a ref on the oldest rolled segment, as an in-flight Read would,
+	// then force the cache over its MaxOpenSegments budget by also
+	// acquiring segment 1.
+	held, err := l.acquire(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, off := range []uint64{1} {
+		ref, err := l.acquire(off)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.release(ref)
+	}
+
+	if held.count() < 2 {
+		t.Fatalf("held ref count: got %d, want >= 2 (cache baseline + our hold)", held.count())
+	}
+
+	// The still-referenced segment must still be readable: eviction must
+	// not have closed its store or index out from under us.
+	if _, err := held.Get().Read(0); err != nil {
+		t.Fatalf("Read through a held ref after cache pressure: %v", err)
+	}
+
+	l.release(held)
+}
+
+func TestSegmentCacheIdleEvictionUnmapsOnlyUnreferenced(t *testing.T) {
+	dir, err := os.MkdirTemp("", "segment-cache-idle-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1
+	c.SegmentIdleTimeout = time.Millisecond
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if _, err := l.Append(&api.Record{Value: []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append(&api.Record{Value: []byte{1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	held, err := l.acquire(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.release(held)
+
+	time.Sleep(10 * time.Millisecond)
+	l.cache.evictIdle()
+
+	if _, err := held.Get().Read(0); err != nil {
+		t.Fatalf("Read through a held ref after evictIdle: %v", err)
+	}
+}
+
+func TestLogReaderReleasesSegmentRefs(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log-reader-release-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 // force a roll after every record
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 4
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := io.Copy(io.Discard, l.Reader()); err != nil {
+		t.Fatalf("drain Reader(): %v", err)
+	}
+
+	for _, baseOffset := range l.cache.allBaseOffsets() {
+		ref, err := l.cache.acquire(baseOffset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// acquire itself adds one reference; a Reader() that released
+		// everything it grabbed should leave the count at exactly that.
+		if got := ref.count(); got != 2 {
+			t.Fatalf("segment %d ref count after Reader() drained and this acquire: got %d, want 2 (Reader() leaked a ref)", baseOffset, got)
+		}
+		l.release(ref)
+	}
+}
+
+// assertNoOrphans fails the test if any lru entry isn't reachable through
+// c.byOffset: exactly the state a Truncate/Retain racing an in-flight
+// acquire's reopen used to leave behind (a live, never-to-be-closed mmap
+// and fds that no future Truncate, Retain, or Close can reach).
+func (c *segmentCache) assertNoOrphans(t *testing.T) {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		cs := e.Value.(*cachedSegment)
+		if _, ok := c.byOffset[cs.baseOffset]; !ok {
+			t.Fatalf("lru entry for segment %d is orphaned: not reachable via c.byOffset", cs.baseOffset)
+		}
+	}
+}
+
+// TestSegmentCacheAcquireDoesNotResurrectTruncatedSegment is a regression
+// test for a race between acquire reopening an unmapped rolled segment
+// and Truncate/Retain removing that same segment while the reopen's disk
+// I/O runs with the cache lock released. Truncate/Retain's refcount gate
+// only protects already-mapped segments, so a concurrent Truncate could
+// previously delete the segment's files and catalog entry while acquire
+// was reopening it, leaving the reopened handle orphaned from c.order/
+// c.byOffset: a leaked mmap and fds nothing would ever close.
+func TestSegmentCacheAcquireDoesNotResurrectTruncatedSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "segment-cache-acquire-truncate-race-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 // force a roll after every record
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	const n = 20
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every rolled segment starts out unmapped from l's perspective once
+	// enough of them have rolled past MaxOpenSegments; acquiring one
+	// forces a reopen, racing it against Truncate removing the same
+	// baseOffset is exactly the window the fix closes.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ref, err := l.acquire(0)
+			if err == nil {
+				l.release(ref)
+			}
+		}()
+	}
+	if err := l.Truncate(uint64(n - 1)); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	l.cache.assertNoOrphans(t)
+
+	for _, baseOffset := range l.cache.allBaseOffsets() {
+		ref, err := l.cache.acquire(baseOffset)
+		if err != nil {
+			t.Fatalf("acquire(%d) after race: %v", baseOffset, err)
+		}
+		if _, err := ref.Get().Read(baseOffset); err != nil {
+			t.Fatalf("Read through catalog-reachable segment %d after race: %v", baseOffset, err)
+		}
+		l.release(ref)
+	}
+}
@@ -0,0 +1,36 @@
+package log
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSegmentIsMaxedByAge(t *testing.T) {
+	dir, err := os.MkdirTemp("", "segment-is-maxed-age-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Config
+	c.Segment.MaxStoreBytes = 1 << 20
+	c.Segment.MaxIndexBytes = 1 << 16
+	c.Segment.MaxAge = 5 * time.Millisecond
+
+	s, err := newSegment(dir, 0, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if s.IsMaxed() {
+		t.Fatal("a brand new segment reported IsMaxed before MaxAge elapsed")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if !s.IsMaxed() {
+		t.Fatal("a segment older than Config.Segment.MaxAge should report IsMaxed")
+	}
+}
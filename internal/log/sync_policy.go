@@ -0,0 +1,17 @@
+package log
+
+// SyncPolicy controls when a segment's store is fsync'd.
+type SyncPolicy string
+
+const (
+	// SyncNone never explicitly syncs; data is only as durable as the OS
+	// page cache until the process exits cleanly.
+	SyncNone SyncPolicy = "none"
+
+	// SyncAlways syncs once after every committed write batch.
+	SyncAlways SyncPolicy = "always"
+
+	// SyncInterval syncs on a timer (Config.Segment.SyncInterval)
+	// independent of how often Append is called.
+	SyncInterval SyncPolicy = "interval"
+)